@@ -0,0 +1,33 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// lookupDedup checks u.DedupIndex for a previously recorded UploadResult
+// for hash. It reuses the same blobstore.Index cache abstraction as
+// lookupMirror/recordMirror.
+func (u *Upload) lookupDedup(hash string) (*UploadResult, bool, error) {
+	value, ok, err := u.DedupIndex.Lookup(context.Background(), hash)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	result := &UploadResult{}
+	if err := json.Unmarshal(value, result); err != nil {
+		return nil, false, fmt.Errorf("can't parse cached dedup index entry (%v)", err)
+	}
+	return result, true, nil
+}
+
+// recordDedup stores result in u.DedupIndex under hash, so a later upload
+// of the same bytes can be short-circuited.
+func (u *Upload) recordDedup(hash string, result *UploadResult) error {
+	value, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("can't serialize dedup index entry (%v)", err)
+	}
+	return u.DedupIndex.Record(context.Background(), hash, value)
+}
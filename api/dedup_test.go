@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"testing"
+)
+
+// memIndex is a minimal in-memory blobstore.Index, just enough to exercise
+// lookupDedup/recordDedup without a real backend.
+type memIndex struct {
+	values map[string][]byte
+}
+
+func (m *memIndex) Lookup(ctx context.Context, hash string) ([]byte, bool, error) {
+	v, ok := m.values[hash]
+	return v, ok, nil
+}
+
+func (m *memIndex) Record(ctx context.Context, hash string, value []byte) error {
+	if m.values == nil {
+		m.values = map[string][]byte{}
+	}
+	m.values[hash] = value
+	return nil
+}
+
+func TestLookupRecordDedup(t *testing.T) {
+	u := &Upload{DedupIndex: &memIndex{}}
+
+	if _, ok, err := u.lookupDedup("abc"); err != nil || ok {
+		t.Fatalf("lookupDedup() on empty index = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+
+	result := &UploadResult{Uploaded: true, ImageID: "img1", ImageUrl: "https://example.com/img1"}
+	if err := u.recordDedup("abc", result); err != nil {
+		t.Fatalf("recordDedup() error = %v", err)
+	}
+
+	got, ok, err := u.lookupDedup("abc")
+	if err != nil {
+		t.Fatalf("lookupDedup() error = %v", err)
+	}
+	if !ok {
+		t.Fatal("lookupDedup() ok = false, want true after recordDedup()")
+	}
+	if *got != *result {
+		t.Fatalf("lookupDedup() = %+v, want %+v", got, result)
+	}
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// hashIfSeekable computes the SHA-256 of stream and rewinds it back to its
+// original position, so the caller can make a mirroring decision before the
+// real upload consumes the stream. It returns ok=false (and no error) for
+// streams that don't implement io.Seeker, since they can only be read once.
+func hashIfSeekable(stream io.Reader) (hash string, ok bool, err error) {
+	seeker, ok := stream.(io.Seeker)
+	if !ok {
+		return "", false, nil
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", false, err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, stream); err != nil {
+		return "", false, err
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return "", false, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), true, nil
+}
+
+// lookupMirror checks u.MirrorIndex for a previously recorded UploadResult
+// for hash.
+func (u *Upload) lookupMirror(hash string) (*UploadResult, bool, error) {
+	value, ok, err := u.MirrorIndex.Lookup(context.Background(), hash)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	result := &UploadResult{}
+	if err := json.Unmarshal(value, result); err != nil {
+		return nil, false, fmt.Errorf("can't parse cached mirror index entry (%v)", err)
+	}
+	return result, true, nil
+}
+
+// recordMirror stores result in u.MirrorIndex under hash, so a later upload
+// of the same bytes can be short-circuited.
+func (u *Upload) recordMirror(hash string, result *UploadResult) error {
+	value, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("can't serialize mirror index entry (%v)", err)
+	}
+	return u.MirrorIndex.Record(context.Background(), hash, value)
+}
+
+// teeToMirror wraps u.Options.Stream so every byte read from it is also
+// written to u.Mirror under hash, keyed by content. It returns the wrapped
+// reader and a finish function that must be called exactly once, on every
+// return path out of Upload() (not just the success one), to reap the
+// goroutine started here. Call it with a nil err once the real upload has
+// completed and the whole stream has been read, to close the mirror write
+// cleanly; call it with a non-nil err on any earlier return, to abort the
+// mirror write instead of leaving the goroutine blocked on the pipe forever.
+func (u *Upload) teeToMirror(hash string) (io.Reader, func(err error) error) {
+	pr, pw := io.Pipe()
+	tee := io.TeeReader(u.Options.Stream, pw)
+
+	done := make(chan error, 1)
+	go func() {
+		err := u.Mirror.Put(context.Background(), hash, pr, u.Options.FileSize)
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	return tee, func(abortErr error) error {
+		if abortErr != nil {
+			pw.CloseWithError(abortErr)
+		} else {
+			pw.Close()
+		}
+		return <-done
+	}
+}
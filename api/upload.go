@@ -3,8 +3,12 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"os"
 	"path"
@@ -14,6 +18,7 @@ import (
 	"log"
 
 	"github.com/simonedegiacomi/gphotosuploader/auth"
+	"github.com/simonedegiacomi/gphotosuploader/blobstore"
 )
 
 var (
@@ -40,6 +45,11 @@ type UploadOptions struct {
 
 	// Optional album name
 	AlbumName string
+
+	// Optional content hash of the photo (typically SHA-256). When set and
+	// a DedupIndex is configured on the Upload, it's used to skip
+	// re-uploading bytes that have already been uploaded successfully.
+	Hash []byte
 }
 
 // NewUploadOptionsFromFile creates a new UploadOptions from a file
@@ -58,6 +68,35 @@ func NewUploadOptionsFromFile(file *os.File) (*UploadOptions, error) {
 	}, nil
 }
 
+// NewUploadOptionsFromFileHashed creates a new UploadOptions from a file,
+// like NewUploadOptionsFromFile, but also pre-computes its SHA-256 into
+// Hash, so Upload uses it directly as the dedup/mirror key instead of
+// hashing the stream itself.
+func NewUploadOptionsFromFileHashed(file *os.File) (*UploadOptions, error) {
+	options, err := NewUploadOptionsFromFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, fmt.Errorf("can't hash file (%v)", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("can't rewind file after hashing (%v)", err)
+	}
+
+	options.Hash = h.Sum(nil)
+	return options, nil
+}
+
+// AlbumResolver resolves a human-readable album title to an idempotent
+// album id, creating the album on first use instead of on every upload.
+// apiv1.Client satisfies this via its EnsureAlbum method.
+type AlbumResolver interface {
+	EnsureAlbum(ctx context.Context, title string) (albumID string, created bool, err error)
+}
+
 // Upload represents an upload, generated by an UploadOptions
 type Upload struct {
 	// Options of the upload
@@ -66,11 +105,35 @@ type Upload struct {
 	// Credentials to used to send the requests
 	Credentials auth.CookieCredentials
 
+	// AlbumResolver, if set, is used to resolve Options.AlbumName into an
+	// album id via EnsureAlbum instead of calling createAlbum
+	// unconditionally, so repeated uploads with the same AlbumName don't
+	// each create a new album.
+	AlbumResolver AlbumResolver
+
 	// URL to which send the request with the image (the real upload)
 	url string
 
 	// Id of the image got from the response of the request that enables the image
 	idToMoveIntoAlbum string
+
+	// Mirror, if set, receives a copy of every successfully uploaded photo
+	// whose content hash is known up front (Options.Hash is set or the
+	// stream is seekable), keyed by that hash, giving a durable local/remote
+	// backup independent of Google Photos. Other streams aren't mirrored.
+	Mirror blobstore.Provider
+
+	// MirrorIndex, if set alongside Mirror, caches sha256 -> UploadResult so
+	// repeated uploads of the same bytes short-circuit and return the
+	// cached result without hitting Google at all.
+	MirrorIndex blobstore.Index
+
+	// DedupIndex, if set, lets Upload skip re-uploading bytes that have
+	// already been uploaded successfully, keyed by content hash. It uses
+	// the same blobstore.Index cache abstraction as MirrorIndex; pass a
+	// distinct instance (e.g. a separate boltindex.Open path) to keep the
+	// two caches independent.
+	DedupIndex blobstore.Index
 }
 
 // NewUpload creates a new Upload given an UploadOptions and a Credentials implementation. This method return an error if the
@@ -118,6 +181,82 @@ func (ur *UploadResult) URLString() string {
 
 // Upload tries to upload an image, making multiple http requests. It returns a response event if there is an error
 func (u *Upload) Upload() (*UploadResult, error) {
+	// Dedup and mirroring both need the content hash up front, and both
+	// accept a stream that's already been hashed (e.g. via
+	// NewUploadOptionsFromFileHashed) or is seekable, so compute it once
+	// and share it between the two instead of each wrapping the stream
+	// independently. Skipped entirely when none of Options.Hash, Mirror,
+	// MirrorIndex or DedupIndex are in play, so callers who never opted
+	// into either feature don't pay for hashing the whole file.
+	var contentHash string
+	var hashed bool
+	if len(u.Options.Hash) > 0 {
+		contentHash = hex.EncodeToString(u.Options.Hash)
+		hashed = true
+	} else if u.Mirror != nil || u.MirrorIndex != nil || u.DedupIndex != nil {
+		var err error
+		contentHash, hashed, err = hashIfSeekable(u.Options.Stream)
+		if err != nil {
+			return &UploadResult{Uploaded: false}, fmt.Errorf("can't hash stream (%v)", err)
+		}
+	}
+
+	// If a dedup index is configured and the hash is already known, skip
+	// the upload entirely when we've already uploaded these exact bytes
+	// before.
+	if u.DedupIndex != nil && hashed {
+		if cached, ok, err := u.lookupDedup(contentHash); err != nil {
+			log.Println("[WARNING] dedup index lookup failed:", err)
+		} else if ok {
+			return cached, nil
+		}
+	}
+
+	// Otherwise, if a dedup index is configured but the stream isn't
+	// seekable (so the hash couldn't be computed up front), hash it as
+	// it's read during the real upload instead, for free, so it can still
+	// be recorded on success. Mirroring stays disabled in this case, same
+	// as it always has been for non-seekable streams.
+	var computedHash hash.Hash
+	if u.DedupIndex != nil && !hashed {
+		computedHash = sha256.New()
+		u.Options.Stream = io.TeeReader(u.Options.Stream, computedHash)
+	}
+
+	// If a mirror index is configured and the hash is known, check whether
+	// we've already uploaded these exact bytes before and, if so, skip the
+	// real upload entirely.
+	if u.MirrorIndex != nil && hashed {
+		if cached, ok, err := u.lookupMirror(contentHash); err != nil {
+			log.Println("[WARNING] mirror index lookup failed:", err)
+		} else if ok {
+			return cached, nil
+		}
+	}
+
+	// If a mirror is configured, tee every byte of the real upload into it
+	// as it's read, so the mirror copy is free of a second read of the file.
+	var finishMirror func(error) error
+	if u.Mirror != nil && hashed {
+		u.Options.Stream, finishMirror = u.teeToMirror(contentHash)
+	}
+
+	// Guarantee finishMirror is called on every return path, not just the
+	// success one at the bottom of this function, so an upload that fails
+	// partway through never leaks the teeToMirror goroutine blocked on its
+	// pipe. mirrorFinished is set once the success path has already called
+	// it cleanly, so this defer becomes a no-op there.
+	mirrorFinished := false
+	if finishMirror != nil {
+		defer func() {
+			if !mirrorFinished {
+				if err := finishMirror(errors.New("upload didn't complete")); err != nil {
+					log.Println("[WARNING] aborting the mirror write failed:", err)
+				}
+			}
+		}()
+	}
+
 	// First request to get the upload url
 	err := u.requestUploadURL()
 	if err != nil {
@@ -153,24 +292,63 @@ func (u *Upload) Upload() (*UploadResult, error) {
 	}
 
 	createdAlbumID := ""
-	// Create album and add the image if needed
+	// Resolve (or create) the album and add the image if needed. When an
+	// AlbumResolver is configured, reuse the existing album on repeated
+	// uploads instead of creating a new one every time.
 	if u.Options.AlbumName != "" {
-		createdAlbumID, err = u.createAlbum(u.Options.AlbumName)
-		if err != nil {
-			log.Println("[WARNING] the file has been uploaded, but the album hasn't been created.")
-			return &UploadResult{
-				Uploaded: true,
-				ImageID:  uploadedImageID,
-				ImageUrl: uploadedImageURL,
-			}, err
+		if u.AlbumResolver != nil {
+			createdAlbumID, _, err = u.AlbumResolver.EnsureAlbum(context.Background(), u.Options.AlbumName)
+			if err != nil {
+				log.Println("[WARNING] the file has been uploaded, but the album hasn't been resolved.")
+				return &UploadResult{
+					Uploaded: true,
+					ImageID:  uploadedImageID,
+					ImageUrl: uploadedImageURL,
+				}, err
+			}
+			u.moveToAlbum(createdAlbumID)
+		} else {
+			createdAlbumID, err = u.createAlbum(u.Options.AlbumName)
+			if err != nil {
+				log.Println("[WARNING] the file has been uploaded, but the album hasn't been created.")
+				return &UploadResult{
+					Uploaded: true,
+					ImageID:  uploadedImageID,
+					ImageUrl: uploadedImageURL,
+				}, err
+			}
 		}
 	}
 
 	// No errors, image uploaded!
-	return &UploadResult{
+	result := &UploadResult{
 		Uploaded: true,
 		ImageID:  uploadedImageID,
 		ImageUrl: uploadedImageURL,
 		AlbumID:  createdAlbumID,
-	}, nil
+	}
+
+	if finishMirror != nil {
+		mirrorFinished = true
+		if err := finishMirror(nil); err != nil {
+			log.Println("[WARNING] mirroring the uploaded file failed:", err)
+		} else if u.MirrorIndex != nil {
+			if err := u.recordMirror(contentHash, result); err != nil {
+				log.Println("[WARNING] recording the mirror index entry failed:", err)
+			}
+		}
+	}
+
+	if u.DedupIndex != nil {
+		if computedHash != nil {
+			contentHash = hex.EncodeToString(computedHash.Sum(nil))
+		}
+		if contentHash != "" {
+			if err := u.recordDedup(contentHash, result); err != nil {
+				log.Println("[WARNING] recording the dedup index entry failed:", err)
+			}
+		}
+	}
+
+	return result, nil
 }
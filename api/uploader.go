@@ -0,0 +1,396 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/simonedegiacomi/gphotosuploader/auth"
+	"github.com/simonedegiacomi/gphotosuploader/blobstore"
+)
+
+// ProgressFunc is called as bytes of an individual upload are sent, so
+// callers can render progress bars. total is the total size of the file
+// being uploaded (opts.FileSize).
+type ProgressFunc func(opts *UploadOptions, bytesSent, total int64)
+
+// RateLimit throttles a batch upload with independent token buckets for
+// uploads per second and bytes per second. A zero value disables the
+// corresponding limit.
+type RateLimit struct {
+	UploadsPerSecond float64
+	BytesPerSecond   float64
+
+	once         sync.Once
+	uploadBucket *rateBucket
+	byteBucket   *rateBucket
+}
+
+func (r *RateLimit) init() {
+	r.once.Do(func() {
+		if r.UploadsPerSecond > 0 {
+			r.uploadBucket = newRateBucket(r.UploadsPerSecond)
+		}
+		if r.BytesPerSecond > 0 {
+			r.byteBucket = newRateBucket(r.BytesPerSecond)
+		}
+	})
+}
+
+func (r *RateLimit) waitUpload(ctx context.Context) error {
+	r.init()
+	if r.uploadBucket == nil {
+		return nil
+	}
+	return r.uploadBucket.take(ctx, 1)
+}
+
+func (r *RateLimit) waitBytes(ctx context.Context, n int64) error {
+	r.init()
+	if r.byteBucket == nil {
+		return nil
+	}
+	return r.byteBucket.take(ctx, float64(n))
+}
+
+// rateBucket is a minimal token-bucket limiter: tokens are added at
+// ratePerSecond and a call to take blocks until enough are available.
+type rateBucket struct {
+	ratePerSecond float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newRateBucket(ratePerSecond float64) *rateBucket {
+	return &rateBucket{ratePerSecond: ratePerSecond, tokens: ratePerSecond, last: time.Now()}
+}
+
+func (b *rateBucket) take(ctx context.Context, n float64) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.ratePerSecond
+		if b.tokens > b.ratePerSecond {
+			b.tokens = b.ratePerSecond
+		}
+		b.last = now
+
+		// A request larger than the bucket's capacity can never be covered
+		// in full, so wait only for a full bucket and let tokens go negative;
+		// later calls then wait off the debt.
+		need := n
+		if need > b.ratePerSecond {
+			need = b.ratePerSecond
+		}
+		if b.tokens >= need {
+			b.tokens -= n
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((need - b.tokens) / b.ratePerSecond * float64(time.Second))
+		b.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// progressReader wraps a reader so every Read reports cumulative progress
+// through onProgress.
+type progressReader struct {
+	io.Reader
+	opts       *UploadOptions
+	total      int64
+	sent       int64
+	onProgress ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.Reader.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		if p.onProgress != nil {
+			p.onProgress(p.opts, p.sent, p.total)
+		}
+	}
+	return n, err
+}
+
+// RetryPolicy configures the exponential backoff used to retry failed
+// uploads. A zero value means no retries.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	d := p.BaseDelay << attempt
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	// Full jitter, to avoid every worker retrying in lockstep.
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// isRetryable reports whether err is worth retrying. The original intent was
+// to retry only transient 429/5xx responses, but requestUploadURL, uploadFile
+// and enablePhoto don't currently surface the HTTP status code of a failed
+// request (they return plain errors.New/fmt.Errorf), so there's no way to
+// distinguish those from a permanent failure like bad credentials or a
+// malformed request here. Once they do, this should check the status code
+// instead of retrying every error. In the meantime, at least don't retry a
+// cancellation/deadline the caller has already given up on.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// Uploader drives a batch of uploads with bounded concurrency, optional rate
+// limiting, retries with exponential backoff, and per-file progress
+// callbacks. The zero value is not usable; create one with NewUploader.
+type Uploader struct {
+	// MaxConcurrency caps the number of uploads in flight at once. Values
+	// <= 0 are treated as 1.
+	MaxConcurrency int
+
+	// RateLimit optionally throttles the batch. Nil disables throttling.
+	RateLimit *RateLimit
+
+	// Retry optionally retries failed uploads with exponential backoff.
+	// The zero value disables retries.
+	Retry RetryPolicy
+
+	// Progress, if set, is called as each file's bytes are sent.
+	Progress ProgressFunc
+
+	// Credentials used to authenticate every upload in the batch.
+	Credentials auth.CookieCredentials
+
+	// Mirror and MirrorIndex, if set, are applied to every upload in the
+	// batch exactly as on a single Upload.
+	Mirror      blobstore.Provider
+	MirrorIndex blobstore.Index
+
+	// DedupIndex, if set, is applied to every upload in the batch exactly
+	// as on a single Upload.
+	DedupIndex blobstore.Index
+
+	// AlbumResolver, if set, is applied to every upload in the batch
+	// exactly as on a single Upload.
+	AlbumResolver AlbumResolver
+}
+
+// NewUploader creates an Uploader that authenticates every upload in a batch
+// with credentials, running up to maxConcurrency of them in parallel.
+func NewUploader(maxConcurrency int, credentials auth.CookieCredentials) *Uploader {
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+	return &Uploader{MaxConcurrency: maxConcurrency, Credentials: credentials}
+}
+
+// FileResult is the outcome of uploading a single *UploadOptions as part of
+// a batch.
+type FileResult struct {
+	Options *UploadOptions
+	Result  *UploadResult
+	Err     error
+}
+
+// BatchResult summarises a call to UploadAll: per-file outcomes plus
+// aggregate counts.
+type BatchResult struct {
+	Files     []FileResult
+	Succeeded int
+	Failed    int
+}
+
+// UploadAll uploads every item in batch, running up to MaxConcurrency of
+// them in parallel. It stops launching new uploads (but lets in-flight ones
+// finish) as soon as ctx is cancelled.
+//
+// Cancellation only stops UploadAll from waiting on in-flight uploads; it
+// can't abort them. api.Upload.Upload() takes no context and its underlying
+// HTTP requests aren't cancelable, so an upload already in flight when ctx
+// is cancelled keeps running to completion in the background even though
+// its FileResult is already reported with ctx.Err().
+func (u *Uploader) UploadAll(ctx context.Context, batch []*UploadOptions) *BatchResult {
+	// MaxConcurrency <= 0 is only clamped to 1 by NewUploader; an Uploader
+	// built with a bare &Uploader{...} literal (needed to set RateLimit,
+	// Retry, Mirror, ...) skips that, so clamp here too instead of handing
+	// make() a zero-size (i.e. unbuffered, permanently blocking) channel.
+	maxConcurrency := u.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+
+	results := make([]FileResult, len(batch))
+	sem := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+
+	for i, opts := range batch {
+		i, opts := i, opts
+
+		select {
+		case <-ctx.Done():
+			results[i] = FileResult{Options: opts, Err: ctx.Err()}
+			continue
+		case sem <- struct{}{}:
+		}
+		// select picks at random when both cases are ready, so re-check
+		// after taking a slot.
+		if err := ctx.Err(); err != nil {
+			<-sem
+			results[i] = FileResult{Options: opts, Err: err}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			result, err := u.uploadOneWithRetry(ctx, opts)
+			results[i] = FileResult{Options: opts, Result: result, Err: err}
+		}()
+	}
+
+	wg.Wait()
+
+	batchResult := &BatchResult{Files: results}
+	for _, r := range results {
+		if r.Err != nil {
+			batchResult.Failed++
+		} else {
+			batchResult.Succeeded++
+		}
+	}
+	return batchResult
+}
+
+func (u *Uploader) uploadOneWithRetry(ctx context.Context, opts *UploadOptions) (*UploadResult, error) {
+	attempts := u.Retry.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	// The stream must be rewound to its start before every retry, since the
+	// previous attempt may have already consumed some (or all) of it;
+	// otherwise a retry would re-upload only the unread tail as if it were
+	// the whole file. Resolve the seeker once up front so a stream that
+	// can't be rewound fails fast instead of silently corrupting a retry.
+	baseStream := opts.Stream
+	var seeker io.Seeker
+	if attempts > 1 {
+		seeker, _ = baseStream.(io.Seeker)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if seeker == nil {
+				return nil, errors.New("can't retry upload: stream doesn't implement io.Seeker")
+			}
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(u.Retry.delay(attempt)):
+			}
+		}
+
+		if u.RateLimit != nil {
+			if err := u.RateLimit.waitUpload(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		result, err := u.uploadOne(ctx, opts, baseStream)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+		if !isRetryable(err) {
+			return nil, err
+		}
+	}
+	return nil, lastErr
+}
+
+// uploadOne runs a single upload attempt, building the progress/rate-limit
+// wrapper chain fresh from baseStream every time so a retried attempt
+// doesn't layer another wrapper on top of (and double rate-limit) the
+// previous attempt's.
+func (u *Uploader) uploadOne(ctx context.Context, opts *UploadOptions, baseStream io.Reader) (*UploadResult, error) {
+	stream := baseStream
+	if u.Progress != nil {
+		stream = &progressReader{Reader: stream, opts: opts, total: opts.FileSize, onProgress: u.Progress}
+	}
+	if u.RateLimit != nil {
+		stream = &rateLimitedReader{Reader: stream, ctx: ctx, limit: u.RateLimit}
+	}
+	opts.Stream = stream
+
+	upload, err := NewUpload(opts, u.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	upload.Mirror = u.Mirror
+	upload.MirrorIndex = u.MirrorIndex
+	upload.DedupIndex = u.DedupIndex
+	upload.AlbumResolver = u.AlbumResolver
+
+	type result struct {
+		r   *UploadResult
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		r, err := upload.Upload()
+		done <- result{r, err}
+	}()
+
+	// upload.Upload() has no context of its own, so this select can only stop
+	// *waiting* on it: on ctx.Done() the goroutine above keeps running its
+	// in-flight HTTP requests to completion in the background, its result
+	// just gets discarded into done (which is why done is buffered).
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-done:
+		return res.r, res.err
+	}
+}
+
+// rateLimitedReader throttles reads to RateLimit.BytesPerSecond.
+type rateLimitedReader struct {
+	io.Reader
+	ctx   context.Context
+	limit *RateLimit
+}
+
+func (r *rateLimitedReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	if n > 0 {
+		if waitErr := r.limit.waitBytes(r.ctx, int64(n)); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRateBucketTake(t *testing.T) {
+	b := newRateBucket(1000) // 1000 tokens/sec, so waits stay short in a unit test
+
+	// The bucket starts full, so taking less than its capacity succeeds
+	// immediately.
+	if err := b.take(context.Background(), 1); err != nil {
+		t.Fatalf("take() error = %v", err)
+	}
+
+	// Taking more than is currently available blocks until enough tokens
+	// accumulate, rather than erroring.
+	start := time.Now()
+	if err := b.take(context.Background(), 1000); err != nil {
+		t.Fatalf("take() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("take() took %v, want it to return once enough tokens accumulate", elapsed)
+	}
+}
+
+func TestRateBucketTakeRespectsContextCancellation(t *testing.T) {
+	b := newRateBucket(1) // 1 token/sec: draining it forces take() to wait
+	b.tokens = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := b.take(ctx, 1); err != ctx.Err() {
+		t.Fatalf("take() error = %v, want %v", err, ctx.Err())
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 0, MaxDelay: time.Second}
+	if d := p.delay(0); d != 0 {
+		t.Fatalf("delay() = %v, want 0 for a zero BaseDelay", d)
+	}
+
+	p = RetryPolicy{BaseDelay: 10 * time.Millisecond, MaxDelay: 15 * time.Millisecond}
+	for attempt := 0; attempt < 5; attempt++ {
+		if d := p.delay(attempt); d > p.MaxDelay {
+			t.Fatalf("delay(%d) = %v, want <= MaxDelay %v", attempt, d, p.MaxDelay)
+		}
+	}
+}
+
+// seekCountingReader wraps a bytes.Reader and counts how many times Seek is
+// called, so a test can tell whether a retry actually rewound the stream.
+type seekCountingReader struct {
+	*bytes.Reader
+	seeks int
+}
+
+func (s *seekCountingReader) Seek(offset int64, whence int) (int64, error) {
+	s.seeks++
+	return s.Reader.Seek(offset, whence)
+}
+
+func TestUploadOneWithRetryRewindsSeekableStreamBetweenAttempts(t *testing.T) {
+	content := []byte("retry me")
+	stream := &seekCountingReader{Reader: bytes.NewReader(content)}
+
+	u := &Uploader{Retry: RetryPolicy{MaxAttempts: 2}}
+	opts := &UploadOptions{Stream: stream, FileSize: int64(len(content))}
+
+	// NewUpload (and the upload itself) can't succeed without a real
+	// auth.CookieCredentials/HTTP round trip, so this only exercises the
+	// seek-on-retry bookkeeping in uploadOneWithRetry, not a full upload.
+	_, _ = u.uploadOneWithRetry(context.Background(), opts)
+
+	if stream.seeks == 0 {
+		t.Fatal("uploadOneWithRetry() never rewound the stream between attempts")
+	}
+}
+
+func TestUploadOneWithRetryFailsFastOnNonSeekableStream(t *testing.T) {
+	stream := io.NopCloser(bytes.NewBufferString("not seekable"))
+	u := &Uploader{Retry: RetryPolicy{MaxAttempts: 2}}
+	opts := &UploadOptions{Stream: stream, FileSize: 12}
+
+	_, err := u.uploadOneWithRetry(context.Background(), opts)
+	if err == nil {
+		t.Fatal("uploadOneWithRetry() error = nil, want an error for a non-seekable stream needing a retry")
+	}
+}
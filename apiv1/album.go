@@ -0,0 +1,183 @@
+package apiv1
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// albumCacheTTL is the default AlbumCacheTTL, used when a Client doesn't set
+// its own.
+const albumCacheTTL = 5 * time.Minute
+
+// Album mirrors the Library API's Album resource, enough of it for callers
+// to enumerate the library (browsers, sync agents) and to find or create an
+// album by title.
+type Album struct {
+	ID                string     `json:"id"`
+	Title             string     `json:"title"`
+	MediaItemsCount   string     `json:"mediaItemsCount"`
+	CoverPhotoBaseURL string     `json:"coverPhotoBaseUrl"`
+	ShareInfo         *ShareInfo `json:"shareInfo,omitempty"`
+}
+
+// ShareInfo mirrors the Library API's ShareInfo object, present on albums
+// that have been shared.
+type ShareInfo struct {
+	ShareableUrl string `json:"shareableUrl"`
+	ShareToken   string `json:"shareToken"`
+	IsJoined     bool   `json:"isJoined"`
+	IsOwned      bool   `json:"isOwned"`
+}
+
+type listAlbumsResponse struct {
+	Albums        []Album `json:"albums"`
+	NextPageToken string  `json:"nextPageToken"`
+}
+
+// ListAlbums returns every album owned by the authenticated user, following
+// pagination to completion. Results are cached in-process for AlbumCacheTTL,
+// so repeated calls (e.g. one per upload to resolve an AlbumName) don't each
+// pay for a full listing.
+func (c *Client) ListAlbums(ctx context.Context) ([]Album, error) {
+	c.albumCacheMu.Lock()
+	defer c.albumCacheMu.Unlock()
+
+	ttl := c.AlbumCacheTTL
+	if ttl <= 0 {
+		ttl = albumCacheTTL
+	}
+	if c.albumCache != nil && time.Since(c.albumCacheAt) < ttl {
+		return c.albumCache, nil
+	}
+
+	// Non-nil even when empty, so an empty listing is cached too.
+	albums := []Album{}
+	pageToken := ""
+	for {
+		page, nextPageToken, err := c.listAlbumsPage(ctx, pageToken)
+		if err != nil {
+			return nil, err
+		}
+		albums = append(albums, page...)
+		if nextPageToken == "" {
+			break
+		}
+		pageToken = nextPageToken
+	}
+
+	c.albumCache = albums
+	c.albumCacheAt = time.Now()
+	return albums, nil
+}
+
+// listAlbumsPage fetches a single page of GET /v1/albums.
+func (c *Client) listAlbumsPage(ctx context.Context, pageToken string) ([]Album, string, error) {
+	query := url.Values{"pageSize": {"50"}}
+	if pageToken != "" {
+		query.Set("pageToken", pageToken)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/albums?"+query.Encode(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp, err := c.Credentials.httpClient(ctx).Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %d listing albums", resp.StatusCode)
+	}
+
+	var parsed listAlbumsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, "", fmt.Errorf("can't parse albums.list response (%v)", err)
+	}
+
+	return parsed.Albums, parsed.NextPageToken, nil
+}
+
+// FindAlbumByTitle returns the first album owned by the authenticated user
+// with the given title, or nil if none matches. The Library API has no
+// server-side filter by title, so this lists (from cache, where possible)
+// and scans.
+func (c *Client) FindAlbumByTitle(ctx context.Context, title string) (*Album, error) {
+	albums, err := c.ListAlbums(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range albums {
+		if albums[i].Title == title {
+			return &albums[i], nil
+		}
+	}
+	return nil, nil
+}
+
+type createAlbumRequest struct {
+	Album struct {
+		Title string `json:"title"`
+	} `json:"album"`
+}
+
+// EnsureAlbum returns the id of the album titled title, creating it if it
+// doesn't already exist. created reports whether a new album was created.
+// It invalidates the album cache on creation, so a subsequent ListAlbums or
+// FindAlbumByTitle sees it. The find-then-create is serialized per Client,
+// so two concurrent EnsureAlbum calls for the same not-yet-existing title
+// can't both create it.
+func (c *Client) EnsureAlbum(ctx context.Context, title string) (albumID string, created bool, err error) {
+	c.ensureMu.Lock()
+	defer c.ensureMu.Unlock()
+
+	existing, err := c.FindAlbumByTitle(ctx, title)
+	if err != nil {
+		return "", false, err
+	}
+	if existing != nil {
+		return existing.ID, false, nil
+	}
+
+	reqBody := createAlbumRequest{}
+	reqBody.Album.Title = title
+	data, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/albums", bytes.NewReader(data))
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.Credentials.httpClient(ctx).Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("unexpected status %d creating album %q", resp.StatusCode, title)
+	}
+
+	var newAlbum Album
+	if err := json.NewDecoder(resp.Body).Decode(&newAlbum); err != nil {
+		return "", false, fmt.Errorf("can't parse albums.create response (%v)", err)
+	}
+
+	c.albumCacheMu.Lock()
+	c.albumCache = nil
+	c.albumCacheMu.Unlock()
+
+	return newAlbum.ID, true, nil
+}
@@ -0,0 +1,110 @@
+package apiv1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+// redirectTransport rewrites every request's scheme and host to target's,
+// so code that calls a hardcoded baseURL can be pointed at an
+// httptest.Server in tests.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// testClient returns a Client whose requests are redirected to server, so
+// tests don't hit the real Library API.
+func testClient(server *httptest.Server) (*Client, context.Context) {
+	target, _ := url.Parse(server.URL)
+	httpClient := &http.Client{Transport: &redirectTransport{target: target}}
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+
+	creds := Credentials{TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"})}
+	return NewClient(creds), ctx
+}
+
+func TestFindAlbumByTitle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"albums":[{"id":"a1","title":"Trip"},{"id":"a2","title":"Other"}]}`))
+	}))
+	defer server.Close()
+
+	c, ctx := testClient(server)
+
+	found, err := c.FindAlbumByTitle(ctx, "Other")
+	if err != nil {
+		t.Fatalf("FindAlbumByTitle() error = %v", err)
+	}
+	if found == nil || found.ID != "a2" {
+		t.Fatalf("FindAlbumByTitle() = %+v, want album a2", found)
+	}
+
+	missing, err := c.FindAlbumByTitle(ctx, "Nope")
+	if err != nil {
+		t.Fatalf("FindAlbumByTitle() error = %v", err)
+	}
+	if missing != nil {
+		t.Fatalf("FindAlbumByTitle() = %+v, want nil for a title with no match", missing)
+	}
+}
+
+func TestEnsureAlbumReusesExistingAlbum(t *testing.T) {
+	createCalls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"albums":[{"id":"a1","title":"Trip"}]}`))
+		case r.Method == http.MethodPost:
+			createCalls++
+			w.Write([]byte(`{"id":"new","title":"Trip"}`))
+		}
+	}))
+	defer server.Close()
+
+	c, ctx := testClient(server)
+
+	id, created, err := c.EnsureAlbum(ctx, "Trip")
+	if err != nil {
+		t.Fatalf("EnsureAlbum() error = %v", err)
+	}
+	if id != "a1" || created {
+		t.Fatalf("EnsureAlbum() = (%q, %v), want (a1, false) for an existing album", id, created)
+	}
+	if createCalls != 0 {
+		t.Fatalf("EnsureAlbum() called create %d times, want 0 for an existing album", createCalls)
+	}
+}
+
+func TestEnsureAlbumCreatesMissingAlbum(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"albums":[]}`))
+		case r.Method == http.MethodPost:
+			w.Write([]byte(`{"id":"new","title":"Trip"}`))
+		}
+	}))
+	defer server.Close()
+
+	c, ctx := testClient(server)
+
+	id, created, err := c.EnsureAlbum(ctx, "Trip")
+	if err != nil {
+		t.Fatalf("EnsureAlbum() error = %v", err)
+	}
+	if id != "new" || !created {
+		t.Fatalf("EnsureAlbum() = (%q, %v), want (new, true) for a missing album", id, created)
+	}
+}
@@ -0,0 +1,408 @@
+package apiv1
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+)
+
+// uploadChunkSize is the amount of bytes sent per PUT/POST request while
+// streaming the file to the upload URL.
+const uploadChunkSize = 8 << 20 // 8 MiB, Google's recommended chunk size
+
+// UploadOptions describes the photo to upload through the Library API. It
+// follows api.UploadOptions without its Timestamp and Hash fields, and adds
+// MimeType plus AlbumPosition, since the Library API lets a caller choose
+// where the item lands inside the target album.
+type UploadOptions struct {
+	// Required field, a stream from which read the image.
+	// You need to close the stream when the image is uploaded
+	Stream io.Reader
+
+	// Required field, size of the photo
+	FileSize int64
+
+	// Name of the photo (optional), sent as the created media item's
+	// description
+	Name string
+
+	// Optional MIME type of the photo, sent as X-Goog-Upload-Content-Type
+	MimeType string
+
+	// Optional album to add the created media item to
+	AlbumId string
+
+	// Optional album title to add the created media item to. If set and
+	// AlbumId isn't, Upload resolves it through Client.EnsureAlbum,
+	// creating the album on first use instead of duplicating it on every
+	// upload.
+	AlbumName string
+
+	// Optional position of the media item inside AlbumId, sent as the
+	// position field of the API's AlbumPosition object (e.g. FIRST_IN_ALBUM).
+	// Left empty to let the API decide. Only that field is sent: the
+	// relative media item or enrichment item id that AFTER_MEDIA_ITEM and
+	// AFTER_ENRICHMENT_ITEM need can't be set.
+	AlbumPosition string
+}
+
+// ResumableUpload is the on-disk state of an in-progress upload: the URL
+// returned by the initial request, how many bytes have been acknowledged so
+// far, and the SHA-256 of the content being uploaded. Persisting it lets an
+// interrupted upload resume from where it left off instead of starting over.
+// NewUpload checks SHA256 against the file passed in on resume, so a stale
+// state file is never used to resume a different one.
+type ResumableUpload struct {
+	UploadURL string `json:"uploadURL"`
+	BytesSent int64  `json:"bytesSent"`
+	SHA256    string `json:"sha256"`
+}
+
+// LoadResumableUpload reads a previously persisted ResumableUpload from path.
+// It returns os.ErrNotExist (wrapped) if no state has been saved yet.
+func LoadResumableUpload(statePath string) (*ResumableUpload, error) {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil, err
+	}
+	state := &ResumableUpload{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("can't parse resumable upload state (%v)", err)
+	}
+	return state, nil
+}
+
+// Save persists the ResumableUpload state to statePath so the upload can be
+// resumed after a process restart.
+func (r *ResumableUpload) Save(statePath string) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("can't serialize resumable upload state (%v)", err)
+	}
+	return os.WriteFile(statePath, data, 0o600)
+}
+
+// hashIfSeekable computes the SHA-256 of stream and rewinds it back to its
+// original position, so the caller can identify its content without
+// consuming it. It returns ok=false (and no error) for streams that don't
+// implement io.Seeker.
+func hashIfSeekable(stream io.Reader) (sha256Hex string, ok bool, err error) {
+	seeker, ok := stream.(io.Seeker)
+	if !ok {
+		return "", false, nil
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", false, err
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, stream); err != nil {
+		return "", false, err
+	}
+	if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+		return "", false, err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), true, nil
+}
+
+// Upload represents an upload to the Library API, generated from an
+// UploadOptions and driven through the documented three-step protocol.
+type Upload struct {
+	// Options of the upload
+	Options *UploadOptions
+
+	// Client used to send the requests
+	Client *Client
+
+	// statePath, if non-empty, is where the ResumableUpload state is
+	// persisted between calls so interrupted uploads can resume.
+	statePath string
+
+	// contentSHA256 is the SHA-256 of Options.Stream, computed up front
+	// when it's seekable. It's recorded in a freshly started
+	// ResumableUpload and checked against a loaded one, so a stale state
+	// file is never resumed against a different file's bytes.
+	contentSHA256 string
+
+	state *ResumableUpload
+}
+
+// NewUpload creates a new Upload given an UploadOptions and a Client. statePath
+// is optional; when set, Upload resumes from any state already saved there,
+// provided its recorded SHA256 matches Options.Stream (only possible when the
+// stream is seekable) -- otherwise the stale state is discarded and the
+// upload starts over, rather than risk resuming against a different file.
+func NewUpload(client *Client, options *UploadOptions, statePath string) (*Upload, error) {
+	if options.Stream == nil {
+		return nil, fmt.Errorf("the stream of the UploadOptions is nil")
+	}
+	if options.FileSize <= 0 {
+		return nil, fmt.Errorf("the fileSize of the UploadOptions is <= 0")
+	}
+	if options.Name == "" {
+		options.Name = path.Base(fmt.Sprintf("upload-%d", options.FileSize))
+	}
+
+	u := &Upload{Options: options, Client: client, statePath: statePath}
+
+	// Only hash the stream up front when resume is actually in play;
+	// callers that never pass a statePath don't pay for it.
+	if statePath != "" {
+		contentSHA256, hashed, err := hashIfSeekable(options.Stream)
+		if err != nil {
+			return nil, fmt.Errorf("can't hash stream (%v)", err)
+		}
+		if hashed {
+			u.contentSHA256 = contentSHA256
+		}
+
+		if state, err := LoadResumableUpload(statePath); err == nil {
+			if hashed && state.SHA256 == contentSHA256 {
+				u.state = state
+			}
+		}
+	}
+	return u, nil
+}
+
+// UploadResult summarises the outcome of a three-step Library API upload.
+type UploadResult struct {
+	Uploaded    bool
+	MediaItemID string
+	ProductURL  string
+	AlbumID     string
+}
+
+// Upload drives the full start/upload/finalize + batchCreate protocol,
+// resuming from persisted state when available.
+func (u *Upload) Upload(ctx context.Context) (*UploadResult, error) {
+	if u.Options.AlbumId == "" && u.Options.AlbumName != "" {
+		albumID, _, err := u.Client.EnsureAlbum(ctx, u.Options.AlbumName)
+		if err != nil {
+			return &UploadResult{Uploaded: false}, fmt.Errorf("can't ensure album %q exists (%v)", u.Options.AlbumName, err)
+		}
+		u.Options.AlbumId = albumID
+	}
+
+	if u.state == nil || u.state.UploadURL == "" {
+		if err := u.requestUploadURL(ctx); err != nil {
+			return &UploadResult{Uploaded: false}, fmt.Errorf("can't get an upload url (%v)", err)
+		}
+	}
+
+	token, err := u.uploadFile(ctx)
+	if err != nil {
+		return &UploadResult{Uploaded: false}, fmt.Errorf("can't upload file to the url obtained from the previous request (%v)", err)
+	}
+
+	item, err := u.batchCreate(ctx, token)
+	if err != nil {
+		return &UploadResult{Uploaded: true}, fmt.Errorf("file uploaded but mediaItems:batchCreate failed (%v)", err)
+	}
+
+	if u.statePath != "" {
+		_ = os.Remove(u.statePath)
+	}
+
+	return item, nil
+}
+
+// requestUploadURL performs step 1: POST /v1/uploads to obtain an upload URL.
+func (u *Upload) requestUploadURL(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/uploads", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Goog-Upload-Command", "start")
+	req.Header.Set("X-Goog-Upload-Content-Type", u.Options.MimeType)
+	req.Header.Set("X-Goog-Upload-Protocol", "resumable")
+	req.Header.Set("X-Goog-Upload-Raw-Size", fmt.Sprintf("%d", u.Options.FileSize))
+
+	resp, err := u.Client.Credentials.httpClient(ctx).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d requesting an upload url", resp.StatusCode)
+	}
+
+	uploadURL := resp.Header.Get("X-Goog-Upload-URL")
+	if uploadURL == "" {
+		return fmt.Errorf("response didn't contain an X-Goog-Upload-URL header")
+	}
+
+	u.state = &ResumableUpload{UploadURL: uploadURL, SHA256: u.contentSHA256}
+	if u.statePath != "" {
+		if err := u.state.Save(u.statePath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadFile performs step 2: chunked upload of the raw bytes to the URL
+// obtained in step 1, resuming from u.state.BytesSent when set, and
+// finalizing on the last chunk. It returns the opaque upload token.
+func (u *Upload) uploadFile(ctx context.Context) (string, error) {
+	if u.state.BytesSent > 0 {
+		if seeker, ok := u.Options.Stream.(io.Seeker); ok {
+			if _, err := seeker.Seek(u.state.BytesSent, io.SeekStart); err != nil {
+				return "", fmt.Errorf("can't resume upload, stream isn't seekable to offset %d (%v)", u.state.BytesSent, err)
+			}
+		} else {
+			return "", fmt.Errorf("can't resume upload, stream doesn't implement io.Seeker")
+		}
+	}
+
+	buf := make([]byte, uploadChunkSize)
+	var token string
+	for {
+		n, readErr := io.ReadFull(u.Options.Stream, buf)
+		if readErr != nil && readErr != io.ErrUnexpectedEOF && readErr != io.EOF {
+			return "", readErr
+		}
+
+		offset := u.state.BytesSent
+		last := u.state.BytesSent+int64(n) >= u.Options.FileSize
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.state.UploadURL, bytes.NewReader(buf[:n]))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("X-Goog-Upload-Offset", fmt.Sprintf("%d", offset))
+		if last {
+			req.Header.Set("X-Goog-Upload-Command", "upload, finalize")
+		} else {
+			req.Header.Set("X-Goog-Upload-Command", "upload")
+		}
+
+		resp, err := u.Client.Credentials.httpClient(ctx).Do(req)
+		if err != nil {
+			return "", err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("unexpected status %d uploading chunk at offset %d", resp.StatusCode, offset)
+		}
+
+		u.state.BytesSent += int64(n)
+		if u.statePath != "" {
+			if err := u.state.Save(u.statePath); err != nil {
+				return "", err
+			}
+		}
+
+		if last {
+			token = string(body)
+			break
+		}
+	}
+
+	if token == "" {
+		return "", fmt.Errorf("finalize response didn't contain an upload token")
+	}
+	return token, nil
+}
+
+type newMediaItem struct {
+	Description     string          `json:"description,omitempty"`
+	SimpleMediaItem simpleMediaItem `json:"simpleMediaItem"`
+}
+
+type simpleMediaItem struct {
+	UploadToken string `json:"uploadToken"`
+}
+
+type albumPosition struct {
+	Position string `json:"position"`
+}
+
+type batchCreateRequest struct {
+	AlbumId       string         `json:"albumId,omitempty"`
+	AlbumPosition *albumPosition `json:"albumPosition,omitempty"`
+	NewMediaItems []newMediaItem `json:"newMediaItems"`
+}
+
+type batchCreateResponse struct {
+	NewMediaItemResults []struct {
+		Status struct {
+			Message string `json:"message"`
+		} `json:"status"`
+		MediaItem struct {
+			ID         string `json:"id"`
+			ProductURL string `json:"productUrl"`
+		} `json:"mediaItem"`
+	} `json:"newMediaItemResults"`
+}
+
+// batchCreate performs step 3: POST /v1/mediaItems:batchCreate with the
+// upload token obtained from step 2, plus the optional album placement.
+func (u *Upload) batchCreate(ctx context.Context, token string) (*UploadResult, error) {
+	reqBody := batchCreateRequest{
+		AlbumId: u.Options.AlbumId,
+		NewMediaItems: []newMediaItem{{
+			Description:     u.Options.Name,
+			SimpleMediaItem: simpleMediaItem{UploadToken: token},
+		}},
+	}
+	if u.Options.AlbumPosition != "" {
+		reqBody.AlbumPosition = &albumPosition{Position: u.Options.AlbumPosition}
+	}
+
+	data, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/mediaItems:batchCreate", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := u.Client.Credentials.httpClient(ctx).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from mediaItems:batchCreate", resp.StatusCode)
+	}
+
+	var parsed batchCreateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("can't parse mediaItems:batchCreate response (%v)", err)
+	}
+	if len(parsed.NewMediaItemResults) != 1 {
+		return nil, fmt.Errorf("expected exactly one result in mediaItems:batchCreate response, got %d", len(parsed.NewMediaItemResults))
+	}
+
+	result := parsed.NewMediaItemResults[0]
+	if result.Status.Message != "" && result.Status.Message != "Success" {
+		return nil, fmt.Errorf("mediaItems:batchCreate reported: %s", result.Status.Message)
+	}
+
+	return &UploadResult{
+		Uploaded:    true,
+		MediaItemID: result.MediaItem.ID,
+		ProductURL:  result.MediaItem.ProductURL,
+		AlbumID:     u.Options.AlbumId,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package apiv1
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewUploadResumesOnlyOnMatchingSHA256(t *testing.T) {
+	content := []byte("the quick brown fox jumps over the lazy dog")
+	statePath := filepath.Join(t.TempDir(), "upload.json")
+
+	newOptions := func() *UploadOptions {
+		return &UploadOptions{Stream: bytes.NewReader(content), FileSize: int64(len(content))}
+	}
+
+	// First call with no saved state: nothing to resume from.
+	first, err := NewUpload(nil, newOptions(), statePath)
+	if err != nil {
+		t.Fatalf("NewUpload() error = %v", err)
+	}
+	if first.state != nil {
+		t.Fatalf("state = %+v, want nil with no saved state", first.state)
+	}
+	if first.contentSHA256 == "" {
+		t.Fatal("contentSHA256 wasn't computed for a seekable stream")
+	}
+
+	// Save a ResumableUpload matching this content's hash, then make sure a
+	// new Upload for the same bytes resumes from it.
+	saved := &ResumableUpload{UploadURL: "https://example.com/upload", BytesSent: 10, SHA256: first.contentSHA256}
+	if err := saved.Save(statePath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	resumed, err := NewUpload(nil, newOptions(), statePath)
+	if err != nil {
+		t.Fatalf("NewUpload() error = %v", err)
+	}
+	if resumed.state == nil || resumed.state.UploadURL != saved.UploadURL {
+		t.Fatalf("state = %+v, want it resumed from the saved state %+v", resumed.state, saved)
+	}
+
+	// A saved state for different bytes must be discarded, not resumed from.
+	stale := &ResumableUpload{UploadURL: "https://example.com/upload", BytesSent: 10, SHA256: "not-the-real-hash"}
+	if err := stale.Save(statePath); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	discarded, err := NewUpload(nil, newOptions(), statePath)
+	if err != nil {
+		t.Fatalf("NewUpload() error = %v", err)
+	}
+	if discarded.state != nil {
+		t.Fatalf("state = %+v, want nil for a stale SHA256 mismatch", discarded.state)
+	}
+}
@@ -0,0 +1,45 @@
+// Package blobstore defines a small, provider-agnostic interface for storing
+// content-addressed blobs, plus a couple of implementations. It's used to
+// keep a durable local/remote mirror of uploaded photos, independent of
+// Google Photos itself.
+package blobstore
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Meta describes a stored blob.
+type Meta struct {
+	// Key the blob was stored under
+	Key string
+
+	// Size of the blob in bytes
+	Size int64
+
+	// ModTime is when the blob was last written
+	ModTime time.Time
+}
+
+// Provider is implemented by anything that can store and retrieve blobs by
+// key. Implementations must be safe for concurrent use.
+type Provider interface {
+	// Put stores size bytes read from r under key, replacing any existing
+	// blob with the same key.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+
+	// Get returns a reader for the blob stored under key. The caller must
+	// close it. It returns an error satisfying errors.Is(err, ErrNotExist)
+	// if no blob is stored under key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the blob stored under key. It is a no-op if the key
+	// doesn't exist.
+	Delete(ctx context.Context, key string) error
+
+	// Stat returns metadata about the blob stored under key, without
+	// reading its content. It returns an error satisfying
+	// errors.Is(err, ErrNotExist) if no blob is stored under key.
+	Stat(ctx context.Context, key string) (Meta, error)
+}
@@ -0,0 +1,68 @@
+// Package boltindex implements blobstore.Index on top of BoltDB, for a
+// simple embedded, zero-dependency cache of content hash to upload result.
+package boltindex
+
+import (
+	"context"
+	"fmt"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/simonedegiacomi/gphotosuploader/blobstore"
+)
+
+var bucketName = []byte("blobstore-index")
+
+// Index is a blobstore.Index backed by a single BoltDB file.
+type Index struct {
+	db *bolt.DB
+}
+
+// Open opens (creating if needed) a BoltDB file at path and returns an Index
+// backed by it. The caller must Close it when done.
+func Open(path string) (*Index, error) {
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't open bolt index %q (%v)", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("can't create bolt bucket (%v)", err)
+	}
+
+	return &Index{db: db}, nil
+}
+
+// Close closes the underlying BoltDB file.
+func (i *Index) Close() error {
+	return i.db.Close()
+}
+
+// Lookup implements blobstore.Index.
+func (i *Index) Lookup(ctx context.Context, hash string) ([]byte, bool, error) {
+	var value []byte
+	err := i.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(bucketName).Get([]byte(hash)); v != nil {
+			value = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return value, value != nil, nil
+}
+
+// Record implements blobstore.Index.
+func (i *Index) Record(ctx context.Context, hash string, value []byte) error {
+	return i.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketName).Put([]byte(hash), value)
+	})
+}
+
+var _ blobstore.Index = (*Index)(nil)
@@ -0,0 +1,50 @@
+package boltindex
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexLookupRecord(t *testing.T) {
+	ctx := context.Background()
+	idx, err := Open(filepath.Join(t.TempDir(), "index.bolt"))
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer idx.Close()
+
+	if _, ok, err := idx.Lookup(ctx, "abc"); err != nil || ok {
+		t.Fatalf("Lookup() on empty index = (_, %v, %v), want (_, false, nil)", ok, err)
+	}
+
+	value := []byte(`{"Uploaded":true}`)
+	if err := idx.Record(ctx, "abc", value); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	got, ok, err := idx.Lookup(ctx, "abc")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if !ok {
+		t.Fatal("Lookup() ok = false, want true after Record()")
+	}
+	if !bytes.Equal(got, value) {
+		t.Fatalf("Lookup() value = %q, want %q", got, value)
+	}
+
+	// Record() replaces any previous entry under the same hash.
+	updated := []byte(`{"Uploaded":true,"ImageID":"2"}`)
+	if err := idx.Record(ctx, "abc", updated); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	got, _, err = idx.Lookup(ctx, "abc")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if !bytes.Equal(got, updated) {
+		t.Fatalf("Lookup() value = %q, want %q after re-Record()", got, updated)
+	}
+}
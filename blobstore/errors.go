@@ -0,0 +1,7 @@
+package blobstore
+
+import "errors"
+
+// ErrNotExist is returned by Get and Stat when the requested key has no
+// blob stored under it.
+var ErrNotExist = errors.New("blobstore: key does not exist")
@@ -0,0 +1,89 @@
+// Package filesystem implements blobstore.Provider on top of a local
+// directory, one file per key.
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/simonedegiacomi/gphotosuploader/blobstore"
+)
+
+// Provider stores blobs as files inside Root, one file per key. Keys are
+// expected to be filesystem-safe (e.g. a hex-encoded SHA-256), since they're
+// used verbatim as file names.
+type Provider struct {
+	Root string
+}
+
+// New creates a Provider rooted at dir, creating it if it doesn't exist yet.
+func New(dir string) (*Provider, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("can't create blobstore root %q (%v)", dir, err)
+	}
+	return &Provider{Root: dir}, nil
+}
+
+func (p *Provider) path(key string) string {
+	return filepath.Join(p.Root, key)
+}
+
+// Put implements blobstore.Provider.
+func (p *Provider) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	tmp, err := os.CreateTemp(p.Root, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("can't create temp file for key %q (%v)", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("can't write blob for key %q (%v)", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), p.path(key))
+}
+
+// Get implements blobstore.Provider.
+func (p *Provider) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(p.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, blobstore.ErrNotExist
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Delete implements blobstore.Provider.
+func (p *Provider) Delete(ctx context.Context, key string) error {
+	err := os.Remove(p.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Stat implements blobstore.Provider.
+func (p *Provider) Stat(ctx context.Context, key string) (blobstore.Meta, error) {
+	info, err := os.Stat(p.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return blobstore.Meta{}, blobstore.ErrNotExist
+	}
+	if err != nil {
+		return blobstore.Meta{}, err
+	}
+	return blobstore.Meta{
+		Key:     key,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}, nil
+}
@@ -0,0 +1,70 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/simonedegiacomi/gphotosuploader/blobstore"
+)
+
+func TestProviderPutGetStatDelete(t *testing.T) {
+	ctx := context.Background()
+	p, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	content := []byte("hello blobstore")
+	if err := p.Put(ctx, "key1", bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	r, err := p.Get(ctx, "key1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Fatalf("Get() content = %q, want %q", got, content)
+	}
+
+	meta, err := p.Stat(ctx, "key1")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if meta.Key != "key1" || meta.Size != int64(len(content)) {
+		t.Fatalf("Stat() = %+v, want Key=key1 Size=%d", meta, len(content))
+	}
+
+	if err := p.Delete(ctx, "key1"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := p.Get(ctx, "key1"); !errors.Is(err, blobstore.ErrNotExist) {
+		t.Fatalf("Get() after Delete() error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestProviderGetStatMissingKey(t *testing.T) {
+	ctx := context.Background()
+	p, err := New(t.TempDir())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if _, err := p.Get(ctx, "missing"); !errors.Is(err, blobstore.ErrNotExist) {
+		t.Fatalf("Get() error = %v, want ErrNotExist", err)
+	}
+	if _, err := p.Stat(ctx, "missing"); !errors.Is(err, blobstore.ErrNotExist) {
+		t.Fatalf("Stat() error = %v, want ErrNotExist", err)
+	}
+	if err := p.Delete(ctx, "missing"); err != nil {
+		t.Fatalf("Delete() of a missing key should be a no-op, got error = %v", err)
+	}
+}
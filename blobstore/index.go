@@ -0,0 +1,14 @@
+package blobstore
+
+import "context"
+
+// Index maps a content hash to the result of a previous, successful upload,
+// so repeated uploads of the same bytes can be short-circuited instead of
+// hitting Google again.
+type Index interface {
+	// Lookup returns the cached value for hash and true if it's known.
+	Lookup(ctx context.Context, hash string) (value []byte, ok bool, err error)
+
+	// Record stores value under hash, replacing any previous entry.
+	Record(ctx context.Context, hash string, value []byte) error
+}
@@ -0,0 +1,140 @@
+// Package s3 implements blobstore.Provider on top of an S3-compatible
+// object store, using aws-sdk-go-v2. Setting a custom Endpoint makes it work
+// against MinIO and other S3-compatible services.
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awshttp "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/simonedegiacomi/gphotosuploader/blobstore"
+)
+
+// Config holds the settings needed to reach an S3-compatible bucket.
+type Config struct {
+	// Bucket to store blobs in
+	Bucket string
+
+	// Region of the bucket
+	Region string
+
+	// Endpoint overrides the default AWS endpoint, for MinIO and other
+	// S3-compatible services. Leave empty to talk to AWS S3.
+	Endpoint string
+
+	// AccessKeyID and SecretAccessKey are static credentials. If
+	// AccessKeyID is empty, no credentials are set on the client: the SDK's
+	// default credential chain (environment, shared config, instance role)
+	// isn't loaded.
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Provider stores blobs as objects in an S3-compatible bucket, keyed by
+// object key.
+type Provider struct {
+	client *awshttp.Client
+	bucket string
+}
+
+// New creates a Provider from Config.
+func New(ctx context.Context, cfg Config) (*Provider, error) {
+	options := awshttp.Options{
+		Region: cfg.Region,
+	}
+	if cfg.Endpoint != "" {
+		options.BaseEndpoint = aws.String(cfg.Endpoint)
+		options.UsePathStyle = true
+	}
+	if cfg.AccessKeyID != "" {
+		options.Credentials = credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, "")
+	}
+
+	return &Provider{
+		client: awshttp.New(options),
+		bucket: cfg.Bucket,
+	}, nil
+}
+
+// Put implements blobstore.Provider.
+func (p *Provider) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	_, err := p.client.PutObject(ctx, &awshttp.PutObjectInput{
+		Bucket:        aws.String(p.bucket),
+		Key:           aws.String(key),
+		Body:          r,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		return fmt.Errorf("can't put object %q (%v)", key, err)
+	}
+	return nil
+}
+
+// Get implements blobstore.Provider.
+func (p *Provider) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := p.client.GetObject(ctx, &awshttp.GetObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return nil, blobstore.ErrNotExist
+	}
+	if err != nil {
+		return nil, fmt.Errorf("can't get object %q (%v)", key, err)
+	}
+	return out.Body, nil
+}
+
+// Delete implements blobstore.Provider.
+func (p *Provider) Delete(ctx context.Context, key string) error {
+	_, err := p.client.DeleteObject(ctx, &awshttp.DeleteObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("can't delete object %q (%v)", key, err)
+	}
+	return nil
+}
+
+// Stat implements blobstore.Provider.
+func (p *Provider) Stat(ctx context.Context, key string) (blobstore.Meta, error) {
+	out, err := p.client.HeadObject(ctx, &awshttp.HeadObjectInput{
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return blobstore.Meta{}, blobstore.ErrNotExist
+	}
+	if err != nil {
+		return blobstore.Meta{}, fmt.Errorf("can't stat object %q (%v)", key, err)
+	}
+	return blobstore.Meta{
+		Key:     key,
+		Size:    aws.ToInt64(out.ContentLength),
+		ModTime: aws.ToTime(out.LastModified),
+	}, nil
+}
+
+func isNotFound(err error) bool {
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return true
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode() == "NoSuchKey" || apiErr.ErrorCode() == "NotFound"
+	}
+	return false
+}